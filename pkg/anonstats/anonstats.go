@@ -0,0 +1,193 @@
+// Package anonstats provides a stable, anonymous identifier for a cluster
+// that survives re-installs of insights-operator: a UUID plus a creation
+// timestamp, elected once via leader election and persisted in a ConfigMap
+// rather than regenerated by every operator replica.
+package anonstats
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+
+	"github.com/openshift/insights-operator/pkg/insightslog"
+)
+
+const (
+	// seedConfigMapName holds the anonymous cluster seed in the operator's
+	// own namespace, so it's visible to cluster-admins and survives pod
+	// restarts without needing its own storage.
+	seedConfigMapName = "insights-operator-anon-seed"
+
+	seedDataKey      = "seed"
+	createdAtDataKey = "created_at"
+
+	leaseDuration = 30 * time.Second
+	renewDeadline = 20 * time.Second
+	retryPeriod   = 5 * time.Second
+
+	// DisableEnvVar lets cluster-admins opt out without touching operator
+	// flags, e.g. via the operator's deployment environment.
+	DisableEnvVar = "INSIGHTS_OPERATOR_DISABLE_ANON_SEED"
+)
+
+// ErrSeedNotReady is returned by EnsureSeed when the seed ConfigMap hasn't
+// been created yet and no replica has become leader to create it. Callers
+// should treat this as "try again next cycle", not as a failure.
+var ErrSeedNotReady = errors.New("anonymous cluster seed not created yet")
+
+// ClusterSeed is the anonymous, stable identifier tagged onto every archive
+// this operator uploads, so archives from the same cluster can be
+// correlated across re-installs without revealing cluster identity.
+type ClusterSeed struct {
+	UUID      string    `json:"uuid"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Disabled reports whether anonymous-seed tagging is turned off, either via
+// --disable-anon-seed (disableFlag) or the DisableEnvVar fallback.
+func Disabled(disableFlag bool) bool {
+	if disableFlag {
+		return true
+	}
+	_, set := os.LookupEnv(DisableEnvVar)
+	return set
+}
+
+// SeedController elects a single replica to create the seed ConfigMap if it
+// doesn't exist yet, and serves the current seed to callers in this process.
+// Only the creation is leader-elected: once the ConfigMap exists, every
+// replica just reads it. Start the election with Run; EnsureSeed itself
+// only ever reads.
+type SeedController struct {
+	client    kubernetes.Interface
+	namespace string
+	identity  string
+	logger    *slog.Logger
+}
+
+// NewSeedController builds a controller that creates/reads the seed
+// ConfigMap in namespace, using identity (typically the pod name) as this
+// replica's leader-election candidate ID.
+func NewSeedController(client kubernetes.Interface, namespace, identity string) *SeedController {
+	return &SeedController{
+		client:    client,
+		namespace: namespace,
+		identity:  identity,
+		logger:    insightslog.ForGatherer(insightslog.Logger(), "anon_seed_controller"),
+	}
+}
+
+// EnsureSeed returns the cluster's anonymous seed. It never blocks on
+// leader election: if the seed ConfigMap doesn't exist yet, it returns
+// ErrSeedNotReady so callers (gather cycles) can skip and retry later
+// instead of stalling on the lease/retry loop. Call Run once at operator
+// startup to actually create the ConfigMap.
+func (c *SeedController) EnsureSeed(ctx context.Context) (*ClusterSeed, error) {
+	seed, err := c.readSeed(ctx)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, ErrSeedNotReady
+		}
+		return nil, err
+	}
+	return seed, nil
+}
+
+// Run elects a single replica to create the seed ConfigMap if it doesn't
+// exist yet, blocking until that has happened (or ctx is done). It is meant
+// to be started once, in its own goroutine, at operator startup, so that
+// EnsureSeed never has to contend for leadership itself.
+func (c *SeedController) Run(ctx context.Context) {
+	if _, err := c.readSeed(ctx); err == nil {
+		return
+	}
+
+	if err := c.electAndCreateSeed(ctx); err != nil {
+		c.logger.ErrorContext(ctx, "unable to elect a leader to create anon-seed config map", "err", err)
+	}
+}
+
+func (c *SeedController) readSeed(ctx context.Context) (*ClusterSeed, error) {
+	cm, err := c.client.CoreV1().ConfigMaps(c.namespace).Get(ctx, seedConfigMapName, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	createdAt, err := time.Parse(time.RFC3339, cm.Data[createdAtDataKey])
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s in %s: %v", createdAtDataKey, seedConfigMapName, err)
+	}
+
+	return &ClusterSeed{UUID: cm.Data[seedDataKey], CreatedAt: createdAt}, nil
+}
+
+func (c *SeedController) electAndCreateSeed(ctx context.Context) error {
+	lock, err := resourcelock.New(
+		resourcelock.ConfigMapsLeasesResourceLock,
+		c.namespace,
+		seedConfigMapName+"-lock",
+		c.client.CoreV1(),
+		c.client.CoordinationV1(),
+		resourcelock.ResourceLockConfig{Identity: c.identity},
+	)
+	if err != nil {
+		return fmt.Errorf("unable to create anon-seed leader election lock: %v", err)
+	}
+
+	electedCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	leaderelection.RunOrDie(electedCtx, leaderelection.LeaderElectionConfig{
+		Lock:          lock,
+		LeaseDuration: leaseDuration,
+		RenewDeadline: renewDeadline,
+		RetryPeriod:   retryPeriod,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(leaderCtx context.Context) {
+				if err := c.createSeedIfAbsent(leaderCtx); err != nil {
+					c.logger.ErrorContext(leaderCtx, "unable to create anon-seed config map", "err", err)
+				}
+				cancel()
+			},
+			OnStoppedLeading: func() {
+				cancel()
+			},
+		},
+	})
+
+	return nil
+}
+
+func (c *SeedController) createSeedIfAbsent(ctx context.Context) error {
+	if _, err := c.readSeed(ctx); err == nil {
+		return nil
+	}
+
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      seedConfigMapName,
+			Namespace: c.namespace,
+		},
+		Data: map[string]string{
+			seedDataKey:      uuid.New().String(),
+			createdAtDataKey: time.Now().UTC().Format(time.RFC3339),
+		},
+	}
+
+	_, err := c.client.CoreV1().ConfigMaps(c.namespace).Create(ctx, cm, metav1.CreateOptions{})
+	if apierrors.IsAlreadyExists(err) {
+		return nil
+	}
+	return err
+}
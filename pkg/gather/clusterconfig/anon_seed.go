@@ -0,0 +1,94 @@
+package clusterconfig
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/prometheus/common/model"
+
+	"github.com/openshift/insights-operator/pkg/anonstats"
+	"github.com/openshift/insights-operator/pkg/insightslog"
+	"github.com/openshift/insights-operator/pkg/record"
+)
+
+// anonSeedRecord is what GatherAnonSeed writes to config/anon_seed.json. It
+// ties the cluster's anonymous seed to the operator version and platform so
+// archives from the same cluster can be correlated across re-installs of
+// insights-operator without identifying the cluster itself.
+type anonSeedRecord struct {
+	Seed            string    `json:"seed"`
+	SeedCreatedAt   time.Time `json:"seed_created_at"`
+	OperatorVersion string    `json:"operator_version"`
+	Platform        string    `json:"platform,omitempty"`
+}
+
+// GatherAnonSeed tags the archive with the cluster's anonymous seed (see
+// pkg/anonstats), the operator's own version, and the platform reported by
+// the virt_platform metric. It is skipped entirely when anonymous-seed
+// tagging has been disabled via --disable-anon-seed or
+// anonstats.DisableEnvVar, and it is skipped for this cycle (without error)
+// if no replica has created the seed ConfigMap yet.
+//
+// Location in archive: config/anon_seed.json
+func GatherAnonSeed(g *Gatherer) func() ([]record.Record, []error) {
+	return func() ([]record.Record, []error) {
+		if anonstats.Disabled(g.disableAnonSeed) || g.anonSeedController == nil {
+			return nil, nil
+		}
+
+		logger := insightslog.ForGatherer(g.logger, "anon_seed")
+
+		seed, err := g.anonSeedController.EnsureSeed(g.ctx)
+		if errors.Is(err, anonstats.ErrSeedNotReady) {
+			logger.InfoContext(g.ctx, "anonymous cluster seed not created yet, skipping this gather cycle")
+			return nil, nil
+		}
+		if err != nil {
+			logger.ErrorContext(g.ctx, "unable to ensure anonymous cluster seed", "err", err)
+			return nil, []error{err}
+		}
+
+		platform, err := gatherVirtPlatform(g.ctx, logger, g)
+		if err != nil {
+			logger.WarnContext(g.ctx, "unable to determine platform for anon seed record", "err", err)
+		}
+
+		data, err := json.Marshal(anonSeedRecord{
+			Seed:            seed.UUID,
+			SeedCreatedAt:   seed.CreatedAt,
+			OperatorVersion: g.operatorVersion,
+			Platform:        platform,
+		})
+		if err != nil {
+			return nil, []error{err}
+		}
+
+		return []record.Record{{Name: "config/anon_seed.json", Item: RawByte(data)}}, nil
+	}
+}
+
+func gatherVirtPlatform(ctx context.Context, logger *slog.Logger, g *Gatherer) (string, error) {
+	api, err := g.PrometheusClient()
+	if err != nil {
+		return "", err
+	}
+
+	result, warnings, err := api.Query(ctx, "virt_platform", time.Now())
+	if err != nil {
+		return "", err
+	}
+	if len(warnings) > 0 {
+		logger.WarnContext(ctx, "virt_platform query returned warnings", "warnings", warnings)
+	}
+
+	vector, ok := result.(model.Vector)
+	if !ok || len(vector) == 0 {
+		return "", fmt.Errorf("no virt_platform sample available")
+	}
+
+	return string(vector[0].Metric["type"]), nil
+}
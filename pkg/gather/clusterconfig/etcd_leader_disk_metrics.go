@@ -0,0 +1,186 @@
+package clusterconfig
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+
+	"github.com/openshift/insights-operator/pkg/insightslog"
+	"github.com/openshift/insights-operator/pkg/record"
+)
+
+const (
+	// etcdLeaderChangeWindow is how far before and after the poll that
+	// detected a leader change we pull disk/proposal/network metrics, giving a
+	// range of samples rather than a single point-in-time snapshot.
+	etcdLeaderChangeWindow = 5 * time.Minute
+
+	// etcdLeaderChangeStep is the resolution used for the range queries
+	// around a transition.
+	etcdLeaderChangeStep = 15 * time.Second
+
+	// etcdLeaderChangeRingBufferSize caps how many transition timestamps are
+	// kept in memory between gather runs within the same operator process, so
+	// the buffer doesn't grow without bound. It is not restart-safe: an operator restart resets the buffer, and the
+	// first observation after a restart is always treated as the baseline
+	// rather than a reportable transition, even if one happened during the
+	// restart window.
+	etcdLeaderChangeRingBufferSize = 50
+)
+
+// etcdLeaderDiskMetricsQueries are evaluated as range queries bracketing
+// every observed leader change.
+var etcdLeaderDiskMetricsQueries = []string{
+	"etcd_disk_wal_fsync_duration_seconds_bucket",
+	"etcd_disk_backend_commit_duration_seconds_bucket",
+	"etcd_server_proposals_committed_total",
+	"etcd_server_proposals_applied_total",
+	"etcd_server_proposals_pending",
+	"etcd_server_proposals_failed_total",
+	"etcd_network_peer_round_trip_time_seconds_bucket",
+}
+
+// leaderChangeRingBuffer keeps the most recently observed
+// etcd_server_leader_changes_seen_total counter value per instance, plus the
+// times of the last etcdLeaderChangeRingBufferSize detected transitions across
+// all instances, so successive gather calls in the same process can detect
+// new transitions without replaying metrics history.
+// State lives only in memory: it does not survive an operator restart.
+type leaderChangeRingBuffer struct {
+	mu       sync.Mutex
+	lastSeen map[string]model.SampleValue
+	recent   []time.Time
+}
+
+func newLeaderChangeRingBuffer() *leaderChangeRingBuffer {
+	return &leaderChangeRingBuffer{lastSeen: map[string]model.SampleValue{}}
+}
+
+// observe records the current counter value for instance and reports whether
+// it increased since the last observation (i.e. a leader change happened).
+func (b *leaderChangeRingBuffer) observe(instance string, value model.SampleValue, at time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	prev, ok := b.lastSeen[instance]
+	b.lastSeen[instance] = value
+	if !ok || value <= prev {
+		return false
+	}
+
+	b.recent = append(b.recent, at)
+	if len(b.recent) > etcdLeaderChangeRingBufferSize {
+		b.recent = b.recent[len(b.recent)-etcdLeaderChangeRingBufferSize:]
+	}
+	return true
+}
+
+// etcdLeaderChanges is shared across gather runs so the ring buffer persists
+// between GatherEtcdLeaderDiskMetrics invocations within the same process.
+// It is reset by an operator restart like any other in-memory state.
+var etcdLeaderChanges = newLeaderChangeRingBuffer()
+
+// GatherEtcdLeaderDiskMetrics polls etcd_server_leader_changes_seen_total
+// and, for every instance whose counter rose since the previous poll,
+// snapshots disk fsync/commit latency, proposal, and peer round-trip metrics
+// from a ±etcdLeaderChangeWindow window around the time of the poll, writing
+// one JSON file per such instance. Several leader changes between two polls
+// yield a single file per instance, and the window is centred on when the
+// change was detected, not on when it happened. It is a no-op unless the operator config opts into
+// etcd-metrics gathering, since it requires access to the cluster's etcd
+// Prometheus metrics.
+//
+// Location in archive: config/etcd/leader_changes/
+func GatherEtcdLeaderDiskMetrics(g *Gatherer) func() ([]record.Record, []error) {
+	return func() ([]record.Record, []error) {
+		if !g.etcdLeaderDiskMetricsEnabled {
+			return nil, nil
+		}
+
+		logger := insightslog.ForGatherer(g.logger, "etcd_leader_disk_metrics")
+
+		api, err := g.PrometheusClient()
+		if err != nil {
+			logger.WarnContext(g.ctx, "unable to create prometheus client, no etcd leader disk metrics will be collected", "err", err)
+			return nil, nil
+		}
+
+		return gatherEtcdLeaderDiskMetrics(g.ctx, logger, api, etcdLeaderChanges, time.Now())
+	}
+}
+
+func gatherEtcdLeaderDiskMetrics(ctx context.Context, logger *slog.Logger, api promv1.API, buf *leaderChangeRingBuffer, now time.Time) ([]record.Record, []error) {
+	result, warnings, err := api.Query(ctx, "etcd_server_leader_changes_seen_total", now)
+	if err != nil {
+		return nil, []error{err}
+	}
+	if len(warnings) > 0 {
+		logger.WarnContext(ctx, "etcd_server_leader_changes_seen_total query returned warnings", "warnings", warnings)
+	}
+
+	vector, ok := result.(model.Vector)
+	if !ok {
+		return nil, []error{fmt.Errorf("unexpected result type %T for etcd_server_leader_changes_seen_total", result)}
+	}
+
+	var records []record.Record
+	var errs []error
+	for _, sample := range vector {
+		instance := string(sample.Metric["instance"])
+		if !buf.observe(instance, sample.Value, now) {
+			continue
+		}
+
+		// All etcd members typically observe a leader change in the same
+		// gather tick, so the filename must carry the instance as well as
+		// the timestamp or their records collide.
+		recordName := fmt.Sprintf("config/etcd/leader_changes/%d_%s.json", now.Unix(), sanitizeInstance(instance))
+		snapshot, err := snapshotEtcdLeaderChange(ctx, insightslog.WithRecord(logger, recordName), api, now)
+		if err != nil {
+			logger.ErrorContext(ctx, "unable to snapshot etcd leader change metrics", "instance", instance, "err", err)
+			errs = append(errs, err)
+			continue
+		}
+
+		records = append(records, record.Record{
+			Name: recordName,
+			Item: RawByte(snapshot),
+		})
+	}
+
+	return records, errs
+}
+
+// sanitizeInstance makes an "instance" label (typically host:port) safe to
+// use as part of an archive file name.
+func sanitizeInstance(instance string) string {
+	return strings.NewReplacer(":", "_", "/", "_").Replace(instance)
+}
+
+func snapshotEtcdLeaderChange(ctx context.Context, logger *slog.Logger, api promv1.API, at time.Time) ([]byte, error) {
+	snapshot := map[string]model.Matrix{}
+
+	for _, query := range etcdLeaderDiskMetricsQueries {
+		result, warnings, err := api.QueryRange(ctx, query, promRange(at))
+		if err != nil {
+			return nil, fmt.Errorf("query %q failed: %v", query, err)
+		}
+		if len(warnings) > 0 {
+			logger.WarnContext(ctx, "etcd leader change query returned warnings", "query", query, "warnings", warnings)
+		}
+		matrix, ok := result.(model.Matrix)
+		if !ok {
+			return nil, fmt.Errorf("unexpected result type %T for query %q", result, query)
+		}
+		snapshot[query] = matrix
+	}
+
+	return json.Marshal(snapshot)
+}
@@ -0,0 +1,46 @@
+package clusterconfig
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+)
+
+func TestLeaderChangeRingBufferObserve(t *testing.T) {
+	buf := newLeaderChangeRingBuffer()
+	now := time.Unix(1000, 0)
+
+	if buf.observe("etcd-0", 1, now) {
+		t.Error("expected the first observation of a new instance not to report a change")
+	}
+	if buf.observe("etcd-0", 1, now.Add(time.Second)) {
+		t.Error("expected an unchanged counter value not to report a change")
+	}
+	if buf.observe("etcd-0", 0, now.Add(2*time.Second)) {
+		t.Error("expected a decreasing counter value not to report a change")
+	}
+	if !buf.observe("etcd-0", 2, now.Add(3*time.Second)) {
+		t.Error("expected an increasing counter value to report a change")
+	}
+	if len(buf.recent) != 1 {
+		t.Fatalf("expected 1 recorded transition, got %d", len(buf.recent))
+	}
+}
+
+func TestLeaderChangeRingBufferCapsSize(t *testing.T) {
+	buf := newLeaderChangeRingBuffer()
+
+	for i := 0; i < etcdLeaderChangeRingBufferSize+10; i++ {
+		buf.observe("etcd-0", model.SampleValue(i+1), time.Unix(int64(i), 0))
+	}
+
+	if len(buf.recent) != etcdLeaderChangeRingBufferSize {
+		t.Fatalf("expected ring buffer to cap at %d entries, got %d", etcdLeaderChangeRingBufferSize, len(buf.recent))
+	}
+
+	oldest := buf.recent[0]
+	if oldest.Unix() != 10 {
+		t.Errorf("expected oldest retained transition at unix time 10, got %d", oldest.Unix())
+	}
+}
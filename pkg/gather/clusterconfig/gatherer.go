@@ -0,0 +1,78 @@
+package clusterconfig
+
+import (
+	"context"
+	"log/slog"
+
+	"k8s.io/client-go/rest"
+
+	"github.com/openshift/insights-operator/pkg/anonstats"
+	"github.com/openshift/insights-operator/pkg/insightslog"
+)
+
+// Gatherer holds the dependencies shared by every config/* gatherer in this
+// package: the request-scoped context and kubeconfig used to reach cluster
+// APIs, plus whatever per-gatherer overrides the operator's controller
+// wires in.
+type Gatherer struct {
+	ctx                     context.Context
+	metricsGatherKubeConfig *rest.Config
+
+	// logger is the root logger every gather func in this package derives
+	// its own child logger from via insightslog.ForGatherer.
+	logger *slog.Logger
+
+	// prometheusRuleQueries overrides defaultPrometheusRuleQueries when
+	// non-nil, letting the operator's controller configure which PromQL
+	// rules GatherPrometheusRules collects.
+	prometheusRuleQueries []PrometheusRuleQuery
+
+	// metricsByteLimit overrides metricsByteLimit (the package default) when
+	// non-zero, letting the operator's controller tune the federate
+	// streaming budget.
+	metricsByteLimit int
+
+	// etcdLeaderDiskMetricsEnabled opts into GatherEtcdLeaderDiskMetrics,
+	// which otherwise stays a no-op since it requires access to the
+	// cluster's etcd Prometheus metrics.
+	etcdLeaderDiskMetricsEnabled bool
+
+	// operatorVersion is tagged onto the anon_seed record so archives can be
+	// correlated with the operator build that produced them.
+	operatorVersion string
+
+	// disableAnonSeed mirrors the operator's --disable-anon-seed flag; see
+	// anonstats.Disabled.
+	disableAnonSeed bool
+
+	// anonSeedController serves the cluster's anonymous seed to
+	// GatherAnonSeed. It is nil when anon-seed tagging isn't wired up by the
+	// operator's controller, in which case GatherAnonSeed is a no-op.
+	anonSeedController *anonstats.SeedController
+}
+
+// NewGatherer builds a Gatherer for the given kubeconfig. prometheusRuleQueries
+// may be left nil and byteLimit left zero, in which case GatherPrometheusRules
+// and GatherMostRecentMetrics fall back to their package defaults.
+func NewGatherer(
+	ctx context.Context,
+	metricsGatherKubeConfig *rest.Config,
+	prometheusRuleQueries []PrometheusRuleQuery,
+	byteLimit int,
+	etcdLeaderDiskMetricsEnabled bool,
+	operatorVersion string,
+	disableAnonSeed bool,
+	anonSeedController *anonstats.SeedController,
+) *Gatherer {
+	return &Gatherer{
+		ctx:                          ctx,
+		metricsGatherKubeConfig:      metricsGatherKubeConfig,
+		logger:                       insightslog.Logger(),
+		prometheusRuleQueries:        prometheusRuleQueries,
+		metricsByteLimit:             byteLimit,
+		etcdLeaderDiskMetricsEnabled: etcdLeaderDiskMetricsEnabled,
+		operatorVersion:              operatorVersion,
+		disableAnonSeed:              disableAnonSeed,
+		anonSeedController:           anonSeedController,
+	}
+}
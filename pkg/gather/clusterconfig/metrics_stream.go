@@ -0,0 +1,128 @@
+package clusterconfig
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+)
+
+// metricsByteLimit bounds the overall, uncompressed size read from a single
+// federate response. It replaces the old "buffer everything then truncate"
+// line limit with a streaming byte budget, so
+// a pathologically large ALERTS or match[] response can't blow up operator
+// memory before truncation even runs.
+const metricsByteLimit = 2 * 1024 * 1024
+
+// streamedMetricsRecord gzips a federate HTTP response on the fly while
+// enforcing metricsByteLimit, recording the total source bytes and lines and
+// whether anything past the limit was dropped. It implements
+// record.Marshalable so the archive writer can treat an in-flight HTTP stream
+// like any other record, without ever holding the full, uncompressed body in
+// memory.
+//
+// Reading and gzip-compressing happen on a separate goroutine, connected by
+// an io.Pipe to Marshal, which collects the compressed output in memory and
+// returns it as a whole once the source is drained.
+type streamedMetricsRecord struct {
+	src       io.ReadCloser
+	byteLimit int
+
+	// bytesRead and linesRead are only valid after Marshal has returned.
+	bytesRead int64
+	linesRead int
+	truncated bool
+}
+
+func newStreamedMetricsRecord(src io.ReadCloser, byteLimit int) *streamedMetricsRecord {
+	return &streamedMetricsRecord{src: src, byteLimit: byteLimit}
+}
+
+// Marshal drains src through a bounded, line-counting reader into a gzip
+// writer, and returns the compressed bytes. Any source bytes beyond
+// byteLimit are discarded (not gzipped) but still counted, so Summary can
+// report the size of the whole source body and whether it was truncated.
+func (s *streamedMetricsRecord) Marshal() ([]byte, error) {
+	defer s.src.Close()
+
+	counting := &lineCountingReader{r: s.src}
+	bounded := io.LimitReader(counting, int64(s.byteLimit))
+
+	pr, pw := io.Pipe()
+	copyErr := make(chan error, 1)
+	go func() {
+		gz := gzip.NewWriter(pw)
+		_, err := io.Copy(gz, bounded)
+		if closeErr := gz.Close(); err == nil {
+			err = closeErr
+		}
+		copyErr <- err
+		pw.CloseWithError(err)
+	}()
+
+	var buf bytes.Buffer
+	if _, err := io.Copy(&buf, pr); err != nil {
+		return nil, err
+	}
+	if err := <-copyErr; err != nil {
+		return nil, err
+	}
+
+	// Anything still unread past byteLimit was never gzipped; drain it
+	// through the same kind of counting reader so the summary comment's
+	// byte/line totals cover the whole source body, not just the gzipped
+	// portion.
+	remaining := &lineCountingReader{r: s.src}
+	if _, err := io.Copy(io.Discard, remaining); err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	s.bytesRead = counting.bytesRead + remaining.bytesRead
+	s.linesRead = counting.linesRead + remaining.linesRead
+	s.truncated = remaining.bytesRead > 0
+
+	return buf.Bytes(), nil
+}
+
+// GetExtension marks this record as gzip-compressed so the archive writer
+// stores it with a .gz suffix instead of re-compressing an already-compressed
+// body.
+func (s *streamedMetricsRecord) GetExtension() string {
+	return "gz"
+}
+
+// Summary renders the truncation comment line previously appended inline to
+// the federate body, now reported out-of-band since the body itself is
+// streamed straight into gzip.
+func (s *streamedMetricsRecord) Summary(name string) string {
+	return fmt.Sprintf("# %s %d lines (%d bytes)%s\n", name, s.linesRead, s.bytesRead, truncatedSuffix(s.truncated))
+}
+
+func truncatedSuffix(truncated bool) string {
+	if truncated {
+		return ", truncated"
+	}
+	return ""
+}
+
+// lineCountingReader counts bytes and newlines as they pass through, without
+// buffering them, so streamedMetricsRecord can report accurate totals after
+// an io.Copy instead of re-reading the body.
+type lineCountingReader struct {
+	r         io.Reader
+	bytesRead int64
+	linesRead int
+}
+
+func (c *lineCountingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.bytesRead += int64(n)
+	for _, b := range p[:n] {
+		if b == '\n' {
+			c.linesRead++
+		}
+	}
+	return n, err
+}
+
+var _ io.Reader = (*lineCountingReader)(nil)
@@ -0,0 +1,84 @@
+package clusterconfig
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestLineCountingReader(t *testing.T) {
+	src := "line one\nline two\nline three"
+	counting := &lineCountingReader{r: strings.NewReader(src)}
+
+	data, err := ioutil.ReadAll(counting)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(data) != src {
+		t.Fatalf("expected reader to pass bytes through unchanged, got %q", data)
+	}
+	if counting.bytesRead != int64(len(src)) {
+		t.Errorf("expected bytesRead %d, got %d", len(src), counting.bytesRead)
+	}
+	if counting.linesRead != 2 {
+		t.Errorf("expected linesRead 2, got %d", counting.linesRead)
+	}
+}
+
+func TestStreamedMetricsRecordMarshalUnderLimit(t *testing.T) {
+	body := "# TYPE ALERTS untyped\nfoo 1\nbar 2\n"
+	rec := newStreamedMetricsRecord(ioutil.NopCloser(strings.NewReader(body)), 1024)
+
+	out, err := rec.Marshal()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	gz, err := gzip.NewReader(bytes.NewReader(out))
+	if err != nil {
+		t.Fatalf("expected gzip output: %v", err)
+	}
+	decompressed, err := ioutil.ReadAll(gz)
+	if err != nil {
+		t.Fatalf("unexpected error decompressing: %v", err)
+	}
+	if string(decompressed) != body {
+		t.Errorf("expected decompressed body %q, got %q", body, decompressed)
+	}
+	if rec.truncated {
+		t.Error("expected truncated to be false when body is under byteLimit")
+	}
+	if rec.bytesRead != int64(len(body)) {
+		t.Errorf("expected bytesRead %d, got %d", len(body), rec.bytesRead)
+	}
+}
+
+func TestStreamedMetricsRecordMarshalTruncates(t *testing.T) {
+	body := "0123456789\n0123456789\n0123456789\n"
+	rec := newStreamedMetricsRecord(ioutil.NopCloser(strings.NewReader(body)), 11)
+
+	out, err := rec.Marshal()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	gz, err := gzip.NewReader(bytes.NewReader(out))
+	if err != nil {
+		t.Fatalf("expected gzip output: %v", err)
+	}
+	decompressed, err := ioutil.ReadAll(gz)
+	if err != nil {
+		t.Fatalf("unexpected error decompressing: %v", err)
+	}
+	if len(decompressed) != 11 {
+		t.Errorf("expected 11 bytes to be gzipped before truncation, got %d", len(decompressed))
+	}
+	if !rec.truncated {
+		t.Error("expected truncated to be true when body exceeds byteLimit")
+	}
+	if rec.bytesRead != int64(len(body)) {
+		t.Errorf("expected bytesRead to count the full source body %d, got %d", len(body), rec.bytesRead)
+	}
+}
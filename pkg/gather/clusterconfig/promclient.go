@@ -0,0 +1,216 @@
+package clusterconfig
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log/slog"
+	"net/http"
+	"time"
+
+	promapi "github.com/prometheus/client_golang/api"
+	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+
+	"github.com/openshift/insights-operator/pkg/insightslog"
+	"github.com/openshift/insights-operator/pkg/record"
+)
+
+const (
+	// thanosQuerierRoute is the in-cluster route exposing the monitoring stack's
+	// Thanos Querier, used as the single entry point for PromQL queries against
+	// both platform and user workload metrics.
+	thanosQuerierRoute = "https://thanos-querier.openshift-monitoring.svc:9091"
+
+	// servingCABundlePath is mounted into the operator pod via the
+	// openshift-service-ca.crt config map and is used to validate the
+	// thanos-querier serving certificate.
+	servingCABundlePath = "/var/run/configmaps/service-ca/service-ca.crt"
+
+	// saTokenPath is the projected service account token used to authenticate
+	// against thanos-querier, which is fronted by the cluster's OAuth proxy.
+	saTokenPath = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+
+	promQueryTimeout = 30 * time.Second
+)
+
+// PrometheusRuleQuery describes a single PromQL expression to gather.
+type PrometheusRuleQuery struct {
+	// Name is used as the JSON file name under config/metrics/<Name>.json.
+	Name string
+	// Query is the PromQL expression, e.g. a recording rule or a raw selector.
+	Query string
+	// Range, when non-zero, switches the query to the range API with this
+	// lookback window ending at "now".
+	Range time.Duration
+	// Step is the resolution step used for range queries.
+	Step time.Duration
+}
+
+// defaultPrometheusRuleQueries is the default set of rules gathered by
+// GatherPrometheusRules. It favors small, high-signal recording rules and
+// SLO burn-rate queries over raw federation.
+var defaultPrometheusRuleQueries = []PrometheusRuleQuery{
+	{Name: "cluster_usage_cpu", Query: "cluster:usage:cpu:sum"},
+	{Name: "cluster_usage_memory", Query: "cluster:usage:memory:sum"},
+	{
+		Name:  "etcd_disk_wal_fsync_duration",
+		Query: "histogram_quantile(0.99, rate(etcd_disk_wal_fsync_duration_seconds_bucket[5m]))",
+		Range: time.Hour,
+		Step:  5 * time.Minute,
+	},
+	{
+		Name:  "etcd_disk_backend_commit_duration",
+		Query: "histogram_quantile(0.99, rate(etcd_disk_backend_commit_duration_seconds_bucket[5m]))",
+		Range: time.Hour,
+		Step:  5 * time.Minute,
+	},
+	{Name: "kube_api_error_budget_burn", Query: "cluster:api_server_requests:burnrate5m"},
+}
+
+// PrometheusClient builds a typed Prometheus API client for the in-cluster
+// thanos-querier route, authenticating with the operator's own service
+// account token and trusting the cluster's serving CA bundle.
+//
+// It replaces ad hoc `/federate` REST calls with the
+// github.com/prometheus/client_golang/api/prometheus/v1 client, giving
+// callers typed model.Vector/model.Matrix results instead of raw
+// exposition text.
+func (g *Gatherer) PrometheusClient() (promv1.API, error) {
+	caCert, err := ioutil.ReadFile(servingCABundlePath)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read serving CA bundle: %v", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caCert) {
+		return nil, fmt.Errorf("unable to parse serving CA bundle %s", servingCABundlePath)
+	}
+
+	token, err := ioutil.ReadFile(saTokenPath)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read service account token: %v", err)
+	}
+
+	client, err := promapi.NewClient(promapi.Config{
+		Address: thanosQuerierRoute,
+		RoundTripper: &bearerTokenRoundTripper{
+			token: string(token),
+			base: &http.Transport{
+				TLSClientConfig: &tls.Config{RootCAs: pool},
+			},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to create prometheus client: %v", err)
+	}
+
+	return promv1.NewAPI(client), nil
+}
+
+// bearerTokenRoundTripper attaches the operator's service account token to
+// every outgoing request, since thanos-querier is fronted by an OAuth proxy
+// that authorizes requests by SAR against the bearer token's identity.
+type bearerTokenRoundTripper struct {
+	token string
+	base  http.RoundTripper
+}
+
+func (rt *bearerTokenRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+rt.token)
+	return rt.base.RoundTrip(req)
+}
+
+// GatherPrometheusRules runs a configurable list of PromQL query/query_range
+// expressions against thanos-querier and serializes the structured results
+// as JSON, one file per rule, under config/metrics/<name>.json.
+//
+// Unlike GatherMostRecentMetrics, this is not limited to the default
+// `/federate` match set: it can gather recording rules and SLI burn-rate
+// queries that are never federated, and the results carry labels instead of
+// being opaque Prometheus text exposition that has to be truncated.
+//
+// Location in archive: config/metrics/
+func GatherPrometheusRules(g *Gatherer) func() ([]record.Record, []error) {
+	return func() ([]record.Record, []error) {
+		logger := insightslog.ForGatherer(g.logger, "prometheus_rules")
+
+		api, err := g.PrometheusClient()
+		if err != nil {
+			logger.WarnContext(g.ctx, "unable to create prometheus client, no rules will be gathered", "err", err)
+			return nil, nil
+		}
+
+		queries := g.prometheusRuleQueries
+		if queries == nil {
+			queries = defaultPrometheusRuleQueries
+		}
+		return gatherPrometheusRules(g.ctx, logger, api, queries)
+	}
+}
+
+func gatherPrometheusRules(ctx context.Context, logger *slog.Logger, api promv1.API, queries []PrometheusRuleQuery) ([]record.Record, []error) {
+	var records []record.Record
+	var errs []error
+
+	for _, q := range queries {
+		start := time.Now()
+		recordName := fmt.Sprintf("config/metrics/%s.json", q.Name)
+		recordLogger := insightslog.WithRecord(logger, recordName)
+		queryCtx, cancel := context.WithTimeout(ctx, promQueryTimeout)
+
+		var result interface{}
+		var warnings promv1.Warnings
+		var err error
+		if q.Range > 0 {
+			now := time.Now()
+			result, warnings, err = api.QueryRange(queryCtx, q.Query, promv1.Range{
+				Start: now.Add(-q.Range),
+				End:   now,
+				Step:  q.Step,
+			})
+		} else {
+			result, warnings, err = api.Query(queryCtx, q.Query, time.Now())
+		}
+		cancel()
+
+		if len(warnings) > 0 {
+			recordLogger.WarnContext(ctx, "prometheus query returned warnings", "query", q.Query, "warnings", warnings)
+		}
+		if err != nil {
+			recordLogger.ErrorContext(ctx, "prometheus query failed", "query", q.Query, "err", err)
+			errs = append(errs, err)
+			continue
+		}
+
+		data, err := json.Marshal(result)
+		if err != nil {
+			recordLogger.ErrorContext(ctx, "unable to marshal prometheus query result", "query", q.Query, "err", err)
+			errs = append(errs, err)
+			continue
+		}
+
+		insightslog.WithBytesWritten(insightslog.WithDuration(recordLogger, time.Since(start)), len(data)).
+			InfoContext(ctx, "gathered prometheus rule", "query", q.Query)
+
+		records = append(records, record.Record{
+			Name: recordName,
+			Item: RawByte(data),
+		})
+	}
+
+	return records, errs
+}
+
+// promRange builds a query_range window centered on at, used by gatherers
+// that need samples bracketing a point-in-time event rather than a fixed
+// lookback from "now".
+func promRange(at time.Time) promv1.Range {
+	return promv1.Range{
+		Start: at.Add(-etcdLeaderChangeWindow),
+		End:   at.Add(etcdLeaderChangeWindow),
+		Step:  etcdLeaderChangeStep,
+	}
+}
@@ -3,23 +3,14 @@ package clusterconfig
 import (
 	"context"
 	"fmt"
-	"io"
-	"io/ioutil"
+	"log/slog"
 
 	"k8s.io/client-go/rest"
-	"k8s.io/klog"
 
+	"github.com/openshift/insights-operator/pkg/insightslog"
 	"github.com/openshift/insights-operator/pkg/record"
 )
 
-const (
-	// metricsAlertsLinesLimit is the maximal number of lines read from monitoring Prometheus
-	// 500 KiB of alerts is limit, one alert line has typically 450 bytes => 1137 lines.
-	// This number has been rounded to 1000 for simplicity.
-	// Formerly, the `500 * 1024 / 450` expression was used instead.
-	metricsAlertsLinesLimit = 1000
-)
-
 // GatherMostRecentMetrics gathers cluster Federated Monitoring metrics.
 //
 // The GET REST query to URL /federate
@@ -28,69 +19,83 @@ const (
 //   etcd_object_counts
 //   cluster_installer
 //   namespace CPU and memory usage
-//   followed by at most 1000 lines of ALERTS metric
+//   followed by at most metricsByteLimit bytes of the ALERTS metric
+//
+// Both the match[] group and the ALERTS metric are streamed straight into
+// gzip as separate archive entries, so neither response is ever buffered in
+// full and a failure on one doesn't discard the other.
 //
 // Location in archive: config/metrics/
 // See: docs/insights-archive-sample/config/metrics
 func GatherMostRecentMetrics(g *Gatherer) func() ([]record.Record, []error) {
 	return func() ([]record.Record, []error) {
+		logger := insightslog.ForGatherer(g.logger, "most_recent_metrics")
+
 		var metricsClient rest.Interface
 		metricsRESTClient, err := rest.RESTClientFor(g.metricsGatherKubeConfig)
 		if err != nil {
-			klog.Warningf("Unable to load metrics client, no metrics will be collected: %v", err)
+			logger.WarnContext(g.ctx, "unable to load metrics client, no metrics will be collected", "err", err)
 		} else {
 			metricsClient = metricsRESTClient
 		}
 		if metricsClient == nil {
 			return nil, nil
 		}
-		return gatherMostRecentMetrics(g.ctx, metricsClient)
+		byteLimit := g.metricsByteLimit
+		if byteLimit == 0 {
+			byteLimit = metricsByteLimit
+		}
+		return gatherMostRecentMetrics(g.ctx, logger, metricsClient, byteLimit)
 	}
 }
-func gatherMostRecentMetrics(ctx context.Context, metricsClient rest.Interface) ([]record.Record, []error) {
-	data, err := metricsClient.Get().AbsPath("federate").
-		Param("match[]", "etcd_object_counts").
-		Param("match[]", "cluster_installer").
-		Param("match[]", "namespace:container_cpu_usage_seconds_total:sum_rate").
-		Param("match[]", "namespace:container_memory_usage_bytes:sum").
-		Param("match[]", "virt_platform").
-		DoRaw(ctx)
+
+func gatherMostRecentMetrics(ctx context.Context, logger *slog.Logger, metricsClient rest.Interface, byteLimit int) ([]record.Record, []error) {
+	var records []record.Record
+	var errs []error
+
+	matches := []string{
+		"etcd_object_counts",
+		"cluster_installer",
+		"namespace:container_cpu_usage_seconds_total:sum_rate",
+		"namespace:container_memory_usage_bytes:sum",
+		"virt_platform",
+	}
+	metrics, err := gatherStreamedFederateMetric(ctx, metricsClient, "config/metrics", "match[]", byteLimit, matches...)
 	if err != nil {
-		// write metrics errors to the file format as a comment
-		klog.Errorf("Unable to retrieve most recent metrics: %v", err)
-		return []record.Record{{Name: "config/metrics", Item: RawByte(fmt.Sprintf("# error: %v\n", err))}}, nil
+		logger.ErrorContext(ctx, "federate failed", "match", matches, "err", err)
+		errs = append(errs, err)
+	} else {
+		records = append(records, metrics...)
 	}
 
-	rsp, err := metricsClient.Get().AbsPath("federate").
-		Param("match[]", "ALERTS").
-		Stream(ctx)
+	alertsMatch := []string{"ALERTS"}
+	alerts, err := gatherStreamedFederateMetric(ctx, metricsClient, "config/metrics_alerts", "match[]", byteLimit, alertsMatch...)
 	if err != nil {
-		// write metrics errors to the file format as a comment
-		klog.Errorf("Unable to retrieve most recent alerts from metrics: %v", err)
-		return []record.Record{{Name: "config/metrics", Item: RawByte(fmt.Sprintf("# error: %v\n", err))}}, nil
-	}
-	r := NewLineLimitReader(rsp, metricsAlertsLinesLimit)
-	alerts, err := ioutil.ReadAll(r)
-	if err != nil && err != io.EOF {
-		klog.Errorf("Unable to read most recent alerts from metrics: %v", err)
-		return nil, []error{err}
+		logger.ErrorContext(ctx, "federate failed", "match", alertsMatch, "err", err)
+		errs = append(errs, err)
+	} else {
+		records = append(records, alerts...)
 	}
 
-	remainingAlertLines, err := countLines(rsp)
-	if err != nil && err != io.EOF {
-		klog.Errorf("Unable to count truncated lines of alerts metric: %v", err)
-		return nil, []error{err}
-	}
-	totalAlertCount := r.GetTotalLinesRead() + remainingAlertLines
+	return records, errs
+}
 
-	// # ALERTS <Total Alerts Lines>/<Alerts Line Limit>
-	// The total number of alerts will typically be greater than the true number of alerts by 2
-	// because the `# TYPE ALERTS untyped` header and the final empty line are counter in.
-	data = append(data, []byte(fmt.Sprintf("# ALERTS %d/%d\n", totalAlertCount, metricsAlertsLinesLimit))...)
-	data = append(data, alerts...)
-	records := []record.Record{
-		{Name: "config/metrics", Item: RawByte(data)},
+// gatherStreamedFederateMetric issues a single /federate request for the
+// given match[] values and returns it as a gzip-streamed record under name,
+// bounded by byteLimit. The request/response body is never buffered in
+// full: it's piped straight into gzip by streamedMetricsRecord.Marshal.
+// If the request itself fails, the record under name instead holds a
+// plain-text "# error: ..." comment, and the returned error is nil.
+func gatherStreamedFederateMetric(ctx context.Context, metricsClient rest.Interface, name, paramName string, byteLimit int, matches ...string) ([]record.Record, error) {
+	req := metricsClient.Get().AbsPath("federate")
+	for _, m := range matches {
+		req = req.Param(paramName, m)
+	}
+	rsp, err := req.Stream(ctx)
+	if err != nil {
+		return []record.Record{{Name: name, Item: RawByte(fmt.Sprintf("# error: %v\n", err))}}, nil
 	}
 
-	return records, nil
+	item := newStreamedMetricsRecord(rsp, byteLimit)
+	return []record.Record{{Name: name, Item: item}}, nil
 }